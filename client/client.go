@@ -2,15 +2,16 @@
 package client
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -24,26 +25,134 @@ type API struct {
 	HTTPClient    *http.Client
 	UserAgent     string
 	AutoReconnect bool
+
+	//RateLimit tracks the request-weight and order-count usage Binance reports on every response.
+	RateLimit *RateLimitState
+
+	//Limiter, if set, is consulted before every request and can block or fail fast to stay under its caps.
+	Limiter RateLimiter
+
+	//Retry configures backoff for idempotent GETs and 5xx responses. DefaultRetryPolicy is used if nil.
+	Retry *RetryPolicy
+
+	//Time, if set, corrects SignedRequest's timestamp for clock drift against Binance's server clock.
+	Time *TimeSource
+
+	//RecvWindow is sent as the optional recvWindow parameter on signed requests, in milliseconds.
+	RecvWindow int64
 }
 
 const (
 	ReconnectLimit = 10
 )
 
-//New initializes API with given URL, api key and secret key. it also provides a way to overwrite *http.Client
-func New(url, key, secretKey string, httpClient *http.Client, userAgent string) *API {
-	return &API{
+//New initializes API with given URL, api key and secret key. it also provides a way to overwrite *http.Client.
+//When syncTime is true, a TimeSource is created and synced against Binance's server clock before returning,
+//so the first SignedRequest already accounts for local clock drift.
+func New(url, key, secretKey string, httpClient *http.Client, userAgent string, syncTime bool) *API {
+	a := &API{
 		URL:           url,
 		Key:           key,
 		SecretKey:     secretKey,
 		HTTPClient:    httpClient,
 		UserAgent:     userAgent,
 		AutoReconnect: true,
+		RateLimit:     &RateLimitState{},
 	}
+
+	if syncTime {
+		a.Time = NewTimeSource(a)
+		if err := a.Time.Sync(); err != nil {
+			log.Println("time sync:", err)
+		}
+	}
+
+	return a
+}
+
+//ListenKeyResponse is the REST response for POST /api/v1/userDataStream; CreateListenKey returns its ListenKey.
+type ListenKeyResponse struct {
+	ListenKey string `json:"listenKey"`
+}
+
+//CreateListenKey starts a new user data stream, returning a listenKey valid for 60 minutes unless kept
+//alive with KeepAliveListenKey.
+func (a *API) CreateListenKey() (string, error) {
+	out := ListenKeyResponse{}
+	err := a.SignedRequest("POST", "/api/v1/userDataStream", nil, &out)
+	return out.ListenKey, err
+}
+
+//KeepAliveListenKey extends the validity of listenKey for another 60 minutes.
+func (a *API) KeepAliveListenKey(listenKey string) error {
+	params := struct {
+		ListenKey string `json:"listenKey"`
+	}{ListenKey: listenKey}
+
+	return a.SignedRequest("PUT", "/api/v1/userDataStream", params, nil)
+}
+
+//CloseListenKey closes the user data stream identified by listenKey.
+func (a *API) CloseListenKey(listenKey string) error {
+	params := struct {
+		ListenKey string `json:"listenKey"`
+	}{ListenKey: listenKey}
+
+	return a.SignedRequest("DELETE", "/api/v1/userDataStream", params, nil)
+}
+
+//ListenKeyKeepAliveInterval is how often Binance recommends pinging a listen key to keep it from expiring.
+const ListenKeyKeepAliveInterval = 30 * time.Minute
+
+//KeepListenKeyAlive pings listenKey every ListenKeyKeepAliveInterval until stop is closed, so the user data
+//stream it backs stays open for as long as the caller needs it.
+func (a *API) KeepListenKeyAlive(listenKey string, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(ListenKeyKeepAliveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := a.KeepAliveListenKey(listenKey); err != nil {
+					log.Println("keepalive:", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+//DepthSnapshot is the REST response for GET /api/v1/depth.
+type DepthSnapshot struct {
+	LastUpdateID int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
+//DepthSnapshot fetches the current order book snapshot for symbol, up to limit price levels per side.
+func (a *API) DepthSnapshot(symbol string, limit int) (DepthSnapshot, error) {
+	out := DepthSnapshot{}
+	params := struct {
+		Symbol string `json:"symbol"`
+		Limit  int    `json:"limit,omitempty"`
+	}{Symbol: symbol, Limit: limit}
+
+	err := a.Request("GET", "/api/v1/depth", params, &out)
+
+	return out, err
 }
 
 //Making a public request to Binance API server.
 func (a *API) Request(method, endpoint string, params interface{}, out interface{}) error {
+	return a.RequestCtx(context.Background(), method, endpoint, params, out)
+}
+
+//RequestCtx is Request with a context.Context, so the caller can cancel the HTTP call or bound it with a
+//deadline. Idempotent GETs are retried with backoff (see RetryPolicy) on transport errors and 5xx
+//responses; 418/429 responses are retried regardless of method, honoring Retry-After when present.
+func (a *API) RequestCtx(ctx context.Context, method, endpoint string, params interface{}, out interface{}) error {
 	url, _ := url.ParseRequestURI(a.URL)
 	url.Path = url.Path + endpoint
 
@@ -58,33 +167,97 @@ func (a *API) Request(method, endpoint string, params interface{}, out interface
 		}
 		url.RawQuery = q.Encode()
 	}
-	log.Printf("%v %v", method, url.String())
-	req, _ := http.NewRequest(method, url.String(), nil)
-
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("X-MBX-APIKEY", a.Key)
-	req.Header.Add("UserAgent", a.UserAgent)
-	res, err := a.HTTPClient.Do(req)
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		type binanceError struct {
-			Code int    `json:"code"`
-			Msg  string `json:"msg"`
-		}
-		e := binanceError{}
-		err = json.NewDecoder(res.Body).Decode(&e)
-		return errors.New(e.Msg)
+	if a.Limiter != nil {
+		if err := a.Limiter.Allow(a.RateLimit); err != nil {
+			return err
+		}
 	}
 
-	if out != nil {
-		err = json.NewDecoder(res.Body).Decode(&out)
+	policy := a.retryPolicy()
+	retryable := method == "GET"
+
+	var lastErr error
+	var delay time.Duration
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if delay == 0 {
+				delay = policy.backoff(attempt)
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay = 0
+		}
+
+		log.Printf("%v %v", method, url.String())
+		req, err := http.NewRequestWithContext(ctx, method, url.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Add("content-type", "application/json")
+		req.Header.Add("X-MBX-APIKEY", a.Key)
+		req.Header.Add("UserAgent", a.UserAgent)
+
+		res, err := a.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			if !retryable {
+				return err
+			}
+			continue
+		}
+
+		a.RateLimit.update(res.Header)
+
+		//418/429 responses are backed off and retried regardless of method, honoring Retry-After when
+		//Binance sends one, so callers don't have to hand-roll rate-limit handling themselves.
+		if res.StatusCode == http.StatusTeapot || res.StatusCode == http.StatusTooManyRequests {
+			apiErr := apiError(res)
+			res.Body.Close()
+			lastErr = apiErr
+			if ae, ok := apiErr.(*APIError); ok && ae.RetryAfter > 0 {
+				delay = ae.RetryAfter
+			}
+			continue
+		}
+
+		if res.StatusCode >= 500 && retryable {
+			lastErr = apiError(res)
+			res.Body.Close()
+			continue
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != 200 {
+			return apiError(res)
+		}
+
+		if out != nil {
+			err = json.NewDecoder(res.Body).Decode(&out)
+		}
+
+		return err
 	}
 
-	return err
+	return lastErr
 }
 
 //Making a signed request to Binance API server.
 func (a *API) SignedRequest(method, endpoint string, params interface{}, out interface{}) error {
+	return a.SignedRequestCtx(context.Background(), method, endpoint, params, out)
+}
+
+//SignedRequestCtx is SignedRequest with a context.Context, so the caller can cancel the HTTP call or bound
+//it with a deadline. Idempotent GETs are retried with backoff (see RetryPolicy) on transport errors and 5xx
+//responses; 418/429 responses are retried regardless of method, honoring Retry-After when present.
+func (a *API) SignedRequestCtx(ctx context.Context, method, endpoint string, params interface{}, out interface{}) error {
 	url, _ := url.ParseRequestURI(a.URL)
 	url.Path = url.Path + endpoint
 
@@ -99,7 +272,10 @@ func (a *API) SignedRequest(method, endpoint string, params interface{}, out int
 	}
 
 	//timestamp is mandatory in signed request
-	q.Add("timestamp", fmt.Sprintf("%v", time.Now().Unix()*1000))
+	q.Add("timestamp", fmt.Sprintf("%v", a.now().UnixNano()/int64(time.Millisecond)))
+	if a.RecvWindow > 0 {
+		q.Add("recvWindow", fmt.Sprintf("%v", a.RecvWindow))
+	}
 
 	mac := hmac.New(sha256.New, []byte(a.SecretKey))
 	mac.Write([]byte(q.Encode()))
@@ -108,47 +284,123 @@ func (a *API) SignedRequest(method, endpoint string, params interface{}, out int
 	//signature needs to be at the last param
 	url.RawQuery = q.Encode() + "&signature=" + signed
 
-	log.Printf("%v %v", method, url.String())
+	if a.Limiter != nil {
+		if err := a.Limiter.Allow(a.RateLimit); err != nil {
+			return err
+		}
+	}
 
-	req, _ := http.NewRequest(method, url.String(), nil)
+	policy := a.retryPolicy()
+	retryable := method == "GET"
 
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("X-MBX-APIKEY", a.Key)
-	req.Header.Add("UserAgent", a.UserAgent)
-	res, err := a.HTTPClient.Do(req)
+	var lastErr error
+	var delay time.Duration
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if delay == 0 {
+				delay = policy.backoff(attempt)
+			}
 
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		type binanceError struct {
-			Code int    `json:"code"`
-			Msg  string `json:"msg"`
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay = 0
 		}
-		e := binanceError{}
-		err = json.NewDecoder(res.Body).Decode(&e)
-		return errors.New(e.Msg)
-	}
-	defer res.Body.Close()
-	if out != nil {
-		err = json.NewDecoder(res.Body).Decode(&out)
+
+		log.Printf("%v %v", method, url.String())
+		req, err := http.NewRequestWithContext(ctx, method, url.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Add("content-type", "application/json")
+		req.Header.Add("X-MBX-APIKEY", a.Key)
+		req.Header.Add("UserAgent", a.UserAgent)
+
+		res, err := a.HTTPClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			if !retryable {
+				return err
+			}
+			continue
+		}
+
+		a.RateLimit.update(res.Header)
+
+		//418/429 responses are backed off and retried regardless of method, honoring Retry-After when
+		//Binance sends one, so callers don't have to hand-roll rate-limit handling themselves.
+		if res.StatusCode == http.StatusTeapot || res.StatusCode == http.StatusTooManyRequests {
+			apiErr := apiError(res)
+			res.Body.Close()
+			lastErr = apiErr
+			if ae, ok := apiErr.(*APIError); ok && ae.RetryAfter > 0 {
+				delay = ae.RetryAfter
+			}
+			continue
+		}
+
+		if res.StatusCode >= 500 && retryable {
+			lastErr = apiError(res)
+			res.Body.Close()
+			continue
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != 200 {
+			return apiError(res)
+		}
+
+		if out != nil {
+			err = json.NewDecoder(res.Body).Decode(&out)
+		}
+
+		return err
 	}
-	return err
+
+	return lastErr
 }
 
 type StreamHandler func(data []byte)
 
-func (a *API) connect(endpoint string) *websocket.Conn {
+func (a *API) connect(ctx context.Context, endpoint string) (*websocket.Conn, error) {
 	url := fmt.Sprintf("wss://stream.binance.com:9443/ws/%s", endpoint)
-	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+
+	dialer := *websocket.DefaultDialer
+	conn, _, err := dialer.DialContext(ctx, url, nil)
 	if err != nil {
-		log.Fatal("dial:", err)
-		return nil
+		return nil, err
 	}
 
-	return conn
+	return conn, nil
+}
+
+//Stream opens endpoint and dispatches every message it receives to handler until the connection is closed
+//or exhausts its reconnect attempts. Use StreamCtx to bound the connection's lifetime with a context.
+func (a *API) Stream(endpoint string, handler StreamHandler) error {
+	return a.StreamCtx(context.Background(), endpoint, handler)
 }
 
-func (a *API) Stream(endpoint string, handler StreamHandler) {
-	websocketClient := a.connect(endpoint)
+//StreamCtx is Stream with a context.Context: cancelling ctx tears the connection down instead of letting it
+//run until Binance closes it or reconnects are exhausted.
+func (a *API) StreamCtx(ctx context.Context, endpoint string, handler StreamHandler) error {
+	websocketClient, err := a.connect(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		websocketClient.Close()
+		mu.Unlock()
+	}()
 
 	go func() {
 		defer websocketClient.Close()
@@ -156,6 +408,10 @@ func (a *API) Stream(endpoint string, handler StreamHandler) {
 		for {
 			_, m, err := websocketClient.ReadMessage()
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
 				log.Println("read:", err)
 				if a.AutoReconnect && reconnects < ReconnectLimit {
 					err := websocketClient.Close()
@@ -164,7 +420,18 @@ func (a *API) Stream(endpoint string, handler StreamHandler) {
 					}
 
 					reconnects++
-					websocketClient = a.connect(endpoint)
+					conn, err := a.connect(ctx, endpoint)
+					if err != nil {
+						log.Println("reconnect:", err)
+						return
+					}
+					mu.Lock()
+					websocketClient = conn
+					mu.Unlock()
+					if ctx.Err() != nil {
+						conn.Close()
+						return
+					}
 					continue
 				}
 
@@ -174,4 +441,118 @@ func (a *API) Stream(endpoint string, handler StreamHandler) {
 		}
 	}()
 
+	return nil
+}
+
+//combinedStreamMessage is the envelope Binance wraps every message in on the combined stream endpoint.
+type combinedStreamMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+//controlRequest is the JSON-RPC style SUBSCRIBE/UNSUBSCRIBE/LIST_SUBSCRIPTIONS control frame documented for
+//the combined stream endpoint.
+type controlRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int64    `json:"id"`
+}
+
+//MultiStreamHandler is called with the stream name a combined-socket message arrived on and its raw payload.
+type MultiStreamHandler func(stream string, data []byte)
+
+//MultiStream is a single websocket connection to Binance's combined stream endpoint that can subscribe to
+//and unsubscribe from individual streams at runtime instead of opening one connection per stream.
+type MultiStream struct {
+	api     *API
+	conn    *websocket.Conn
+	handler MultiStreamHandler
+	ctx     context.Context
+
+	mu     sync.Mutex
+	nextID int64
+}
+
+//NewMultiStream dials Binance's combined stream endpoint and starts dispatching demultiplexed messages to handler.
+func (a *API) NewMultiStream(handler MultiStreamHandler) (*MultiStream, error) {
+	return a.NewMultiStreamCtx(context.Background(), handler)
+}
+
+//NewMultiStreamCtx is NewMultiStream with a context.Context: cancelling ctx tears the connection down.
+func (a *API) NewMultiStreamCtx(ctx context.Context, handler MultiStreamHandler) (*MultiStream, error) {
+	dialer := *websocket.DefaultDialer
+	//the streams query is omitted on purpose: Binance accepts a bare /stream connection and expects
+	//SUBSCRIBE frames to populate it, whereas /stream?streams= with an empty list is rejected or closed
+	//shortly after connecting.
+	conn, _, err := dialer.DialContext(ctx, "wss://stream.binance.com:9443/stream", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MultiStream{api: a, conn: conn, handler: handler, ctx: ctx}
+	go m.readLoop()
+	go func() {
+		<-ctx.Done()
+		m.conn.Close()
+	}()
+
+	return m, nil
+}
+
+func (m *MultiStream) readLoop() {
+	for {
+		_, raw, err := m.conn.ReadMessage()
+		if err != nil {
+			if m.ctx.Err() == nil {
+				log.Println("read:", err)
+			}
+			return
+		}
+
+		msg := combinedStreamMessage{}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+
+		if msg.Stream == "" {
+			//SUBSCRIBE/UNSUBSCRIBE acks and the LIST_SUBSCRIPTIONS result come back as {"result":...,"id":N}
+			//with no "stream" or "data" field; deliver the raw frame to handler under an empty stream name
+			//rather than dropping it.
+			m.handler("", raw)
+			continue
+		}
+
+		//handler is called synchronously, in read order, so per-stream message order is preserved - callers
+		//like OrderBook rely on depth diffs arriving in sequence.
+		m.handler(msg.Stream, msg.Data)
+	}
+}
+
+func (m *MultiStream) send(method string, streams []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	return m.conn.WriteJSON(controlRequest{Method: method, Params: streams, ID: m.nextID})
+}
+
+//Subscribe adds streams to the connection.
+func (m *MultiStream) Subscribe(streams ...string) error {
+	return m.send("SUBSCRIBE", streams)
+}
+
+//Unsubscribe removes streams from the connection.
+func (m *MultiStream) Unsubscribe(streams ...string) error {
+	return m.send("UNSUBSCRIBE", streams)
+}
+
+//ListSubscriptions requests the list of streams currently subscribed on the connection. The response
+//arrives asynchronously on the MultiStreamHandler, like any other message, with an empty stream name.
+func (m *MultiStream) ListSubscriptions() error {
+	return m.send("LIST_SUBSCRIPTIONS", nil)
+}
+
+//Close closes the underlying connection.
+func (m *MultiStream) Close() error {
+	return m.conn.Close()
 }
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//APIError is returned when Binance's REST API responds with a non-200 status. StatusCode and RetryAfter let
+//callers distinguish rate-limit responses (418/429) from ordinary API errors.
+type APIError struct {
+	Code       int
+	Msg        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance: %s (code %d, status %d)", e.Msg, e.Code, e.StatusCode)
+}
+
+//apiError decodes a non-200 Binance response into an *APIError, including Retry-After on 418/429 responses.
+func apiError(res *http.Response) error {
+	e := struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	json.NewDecoder(res.Body).Decode(&e)
+
+	err := &APIError{Code: e.Code, Msg: e.Msg, StatusCode: res.StatusCode}
+	if res.StatusCode == http.StatusTeapot || res.StatusCode == http.StatusTooManyRequests {
+		if s := res.Header.Get("Retry-After"); s != "" {
+			if secs, parseErr := strconv.Atoi(s); parseErr == nil {
+				err.RetryAfter = time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	return err
+}
+
+//RateLimitState tracks the request-weight and order-count usage Binance reports on every response, via the
+//X-MBX-USED-WEIGHT* and X-MBX-ORDER-COUNT-* headers.
+type RateLimitState struct {
+	mu          sync.Mutex
+	UsedWeight  int
+	OrderCounts map[string]int
+}
+
+func (r *RateLimitState) update(header http.Header) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.OrderCounts == nil {
+		r.OrderCounts = map[string]int{}
+	}
+
+	for k, v := range header {
+		if len(v) == 0 {
+			continue
+		}
+
+		n, err := strconv.Atoi(v[0])
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(k, "X-Mbx-Used-Weight"):
+			r.UsedWeight = n
+		case strings.HasPrefix(k, "X-Mbx-Order-Count-"):
+			r.OrderCounts[k] = n
+		}
+	}
+}
+
+//RateLimiter is consulted before every request and may block to stay under a configured cap, or return an
+//error to fail the request fast instead of sending it to Binance.
+type RateLimiter interface {
+	Allow(state *RateLimitState) error
+}
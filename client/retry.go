@@ -0,0 +1,38 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+//RetryPolicy configures exponential backoff with jitter, used by RequestCtx/SignedRequestCtx when retrying
+//idempotent GETs and 5xx responses.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+//DefaultRetryPolicy is used whenever API.Retry is nil.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay * time.Duration(uint64(1)<<uint(attempt))
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2+1)))
+}
+
+func (a *API) retryPolicy() RetryPolicy {
+	if a.Retry != nil {
+		return *a.Retry
+	}
+
+	return DefaultRetryPolicy
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+//serverTimeResponse is the REST response for GET /api/v1/time.
+type serverTimeResponse struct {
+	ServerTime int64 `json:"serverTime"`
+}
+
+//TimeSource keeps SignedRequest's timestamp in sync with Binance's server clock by periodically calling
+//GET /api/v1/time and tracking the drift between it and the local clock. Binance rejects signed requests
+//whose timestamp drifts more than recvWindow ms from server time, which a plain time.Now() can't guarantee
+//on a machine with clock skew.
+type TimeSource struct {
+	api *API
+
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+//NewTimeSource creates a TimeSource for api. Call Sync (or Start) before relying on Now/Offset.
+func NewTimeSource(api *API) *TimeSource {
+	return &TimeSource{api: api}
+}
+
+//Sync fetches the current server time and updates the drift offset.
+func (t *TimeSource) Sync() error {
+	out := serverTimeResponse{}
+
+	before := time.Now()
+	if err := t.api.Request("GET", "/api/v1/time", nil, &out); err != nil {
+		return err
+	}
+	latency := time.Since(before) / 2
+
+	serverNow := time.Unix(0, out.ServerTime*int64(time.Millisecond)).Add(latency)
+
+	t.mu.Lock()
+	t.offset = serverNow.Sub(time.Now())
+	t.mu.Unlock()
+
+	return nil
+}
+
+//Offset returns the drift between the local clock and Binance's server clock as of the last Sync.
+func (t *TimeSource) Offset() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.offset
+}
+
+//Now returns the local time adjusted by the drift offset from the last Sync.
+func (t *TimeSource) Now() time.Time {
+	return time.Now().Add(t.Offset())
+}
+
+//Start runs an initial Sync and then resyncs every interval until stop is closed.
+func (t *TimeSource) Start(interval time.Duration, stop <-chan struct{}) error {
+	if err := t.Sync(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.Sync(); err != nil {
+					log.Println("time sync:", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+//now returns a.Time.Now() if a time source is configured, or the local clock otherwise.
+func (a *API) now() time.Time {
+	if a.Time != nil {
+		return a.Time.Now()
+	}
+
+	return time.Now()
+}
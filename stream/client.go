@@ -1,9 +1,11 @@
 package stream
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/apisit/binance-go/client"
 )
@@ -11,23 +13,123 @@ import (
 type DepthHandler func(data DepthStream)
 type KlineHandler func(data KlineStream)
 
+//Subscription identifies a single symbol/channel pair on the combined stream, e.g. {"btcusdt", "depth"}.
+type Subscription struct {
+	Symbol  string
+	Channel string
+}
+
+func (s Subscription) name() string {
+	return fmt.Sprintf("%s@%s", strings.ToLower(s.Symbol), s.Channel)
+}
+
 type Client struct {
 	API client.API
+
+	//Ctx bounds the combined stream connection's lifetime; cancelling it tears down all subscriptions.
+	//context.Background() is used if nil.
+	Ctx context.Context
+
+	mu       sync.Mutex
+	multi    *client.MultiStream
+	handlers map[string][]func(data []byte)
 }
 
 //Methods for stream endpoints
 type Interface interface {
-	Depth(params DepthParams, handler DepthHandler)
-	Kline(params KlineParams, handler KlineHandler)
+	Depth(params DepthParams, handler DepthHandler) error
+	Kline(params KlineParams, handler KlineHandler) error
+	User(listenKey string, handler UserDataHandler) error
+	Subscribe(sub Subscription, handler func(data []byte)) error
+	Unsubscribe(sub Subscription) error
+	OrderBook(symbol string) (*OrderBook, error)
+	Close() error
 }
 
 var _ Interface = (*Client)(nil)
 
+//ensure lazily dials the combined stream connection, starting it the first time a subscription is made.
+func (c *Client) ensure() (*client.MultiStream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.multi != nil {
+		return c.multi, nil
+	}
+
+	c.handlers = map[string][]func(data []byte){}
+
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	multi, err := c.API.NewMultiStreamCtx(ctx, func(stream string, data []byte) {
+		c.mu.Lock()
+		handlers := c.handlers[stream]
+		c.mu.Unlock()
+
+		for _, handler := range handlers {
+			handler(data)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.multi = multi
+
+	return c.multi, nil
+}
+
+//Close tears down the combined stream connection and all of its subscriptions.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.multi == nil {
+		return nil
+	}
+
+	return c.multi.Close()
+}
+
+//Subscribe opens (or reuses) the combined stream connection and routes messages for sub to handler. Symbols
+//and channels can be added and removed at runtime without opening a new TCP connection. Subscribing the same
+//sub more than once fans out to every registered handler instead of replacing the previous one, so Depth,
+//Kline and OrderBook can all watch the same symbol/channel concurrently.
+func (c *Client) Subscribe(sub Subscription, handler func(data []byte)) error {
+	multi, err := c.ensure()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.handlers[sub.name()] = append(c.handlers[sub.name()], handler)
+	c.mu.Unlock()
+
+	return multi.Subscribe(sub.name())
+}
+
+//Unsubscribe removes sub from the combined stream connection and stops routing its messages.
+func (c *Client) Unsubscribe(sub Subscription) error {
+	c.mu.Lock()
+	multi := c.multi
+	delete(c.handlers, sub.name())
+	c.mu.Unlock()
+
+	if multi == nil {
+		return nil
+	}
+
+	return multi.Unsubscribe(sub.name())
+}
+
 //Stream for depth endpoint
-func (c *Client) Depth(params DepthParams, handler DepthHandler) {
-	endpoint := fmt.Sprintf("%s@depth", strings.ToLower(params.Symbol))
+func (c *Client) Depth(params DepthParams, handler DepthHandler) error {
+	sub := Subscription{Symbol: params.Symbol, Channel: "depth"}
 
-	c.API.Stream(endpoint, func(d []byte) {
+	return c.Subscribe(sub, func(d []byte) {
 		out := DepthStream{}
 		json.Unmarshal(d, &out)
 		go handler(out)
@@ -35,11 +137,12 @@ func (c *Client) Depth(params DepthParams, handler DepthHandler) {
 }
 
 //Stream for kline endpoint
-func (c *Client) Kline(params KlineParams, handler KlineHandler) {
-	endpoint := fmt.Sprintf("%s@kline_%s", strings.ToLower(params.Symbol), params.Interval)
-	c.API.Stream(endpoint, func(d []byte) {
+func (c *Client) Kline(params KlineParams, handler KlineHandler) error {
+	sub := Subscription{Symbol: params.Symbol, Channel: fmt.Sprintf("kline_%s", params.Interval)}
+
+	return c.Subscribe(sub, func(d []byte) {
 		out := KlineStream{}
 		json.Unmarshal(d, &out)
 		go handler(out)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/apisit/binance-go/client"
+)
+
+//DepthFrame is a single @depth diff event as documented by Binance.
+type DepthFrame struct {
+	EventType     string     `json:"e"`
+	EventTime     int64      `json:"E"`
+	Symbol        string     `json:"s"`
+	FirstUpdateID int64      `json:"U"`
+	FinalUpdateID int64      `json:"u"`
+	Bids          [][]string `json:"b"`
+	Asks          [][]string `json:"a"`
+}
+
+//OrderBookHandler is called with the full bid/ask maps, keyed by price, after a diff is applied.
+type OrderBookHandler func(bids, asks map[float64]float64)
+
+//OrderBook maintains a local view of a symbol's order book from its @depth diff stream, following Binance's
+//documented "How to manage a local order book correctly" procedure: buffer diffs until the REST snapshot is
+//fetched, drop diffs already covered by it, verify the first applicable diff bridges the snapshot, then
+//apply the rest in order. If the update sequence ever breaks, the book resyncs from a fresh snapshot.
+type OrderBook struct {
+	API    client.API
+	Symbol string
+	Limit  int
+
+	mu       sync.Mutex
+	bids     map[float64]float64
+	asks     map[float64]float64
+	lastID   int64
+	buffered []DepthFrame
+	ready    bool
+
+	onReady  func()
+	onUpdate OrderBookHandler
+}
+
+//OnReady registers a callback invoked each time a snapshot has been loaded and reconciled: after the initial
+//load and again after every resync.
+func (o *OrderBook) OnReady(fn func()) {
+	o.onReady = fn
+}
+
+//OnUpdate registers a callback invoked after every diff applied to the book.
+func (o *OrderBook) OnUpdate(fn OrderBookHandler) {
+	o.onUpdate = fn
+}
+
+//Load fetches the REST snapshot, reconciles it against any diffs buffered so far, and marks the book ready.
+func (o *OrderBook) Load() error {
+	snapshot, err := o.API.DepthSnapshot(o.Symbol, o.Limit)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+
+	o.bids = map[float64]float64{}
+	o.asks = map[float64]float64{}
+	for _, b := range snapshot.Bids {
+		applyLevel(o.bids, b)
+	}
+	for _, a := range snapshot.Asks {
+		applyLevel(o.asks, a)
+	}
+	o.lastID = snapshot.LastUpdateID
+	o.ready = false
+
+	buffered := o.buffered
+	o.buffered = nil
+
+	gap := false
+	for _, frame := range buffered {
+		if frame.FinalUpdateID <= o.lastID {
+			continue
+		}
+		if frame.FirstUpdateID > o.lastID+1 {
+			//there's a gap between the snapshot (or the previous diff) and this diff; resync from a fresh snapshot.
+			gap = true
+			break
+		}
+		o.applyFrame(frame)
+	}
+
+	if !gap {
+		o.ready = true
+	}
+
+	o.mu.Unlock()
+
+	if gap {
+		return o.Load()
+	}
+
+	if o.onReady != nil {
+		go o.onReady()
+	}
+
+	return nil
+}
+
+//Apply feeds a single @depth diff event into the book, buffering it until Load has run and triggering a
+//resync if the update sequence breaks.
+func (o *OrderBook) Apply(frame DepthFrame) {
+	o.mu.Lock()
+	if !o.ready {
+		o.buffered = append(o.buffered, frame)
+		o.mu.Unlock()
+		return
+	}
+
+	if frame.FirstUpdateID > o.lastID+1 {
+		o.ready = false
+		o.mu.Unlock()
+		go o.Load()
+		return
+	}
+
+	if frame.FinalUpdateID <= o.lastID {
+		o.mu.Unlock()
+		return
+	}
+
+	o.applyFrame(frame)
+	o.mu.Unlock()
+}
+
+//applyFrame merges a diff into the book and advances lastID. Callers must hold mu.
+func (o *OrderBook) applyFrame(frame DepthFrame) {
+	for _, b := range frame.Bids {
+		applyLevel(o.bids, b)
+	}
+	for _, a := range frame.Asks {
+		applyLevel(o.asks, a)
+	}
+	o.lastID = frame.FinalUpdateID
+
+	if o.onUpdate != nil {
+		bids := cloneLevels(o.bids)
+		asks := cloneLevels(o.asks)
+		go o.onUpdate(bids, asks)
+	}
+}
+
+//applyLevel merges a single [price, quantity] diff entry into levels, removing the price when quantity is 0.
+func applyLevel(levels map[float64]float64, level []string) {
+	if len(level) != 2 {
+		return
+	}
+
+	price, err := strconv.ParseFloat(level[0], 64)
+	if err != nil {
+		return
+	}
+
+	qty, err := strconv.ParseFloat(level[1], 64)
+	if err != nil {
+		return
+	}
+
+	if qty == 0 {
+		delete(levels, price)
+		return
+	}
+
+	levels[price] = qty
+}
+
+func cloneLevels(levels map[float64]float64) map[float64]float64 {
+	out := make(map[float64]float64, len(levels))
+	for k, v := range levels {
+		out[k] = v
+	}
+
+	return out
+}
+
+//OrderBook subscribes to symbol's @depth diff stream and returns a maintained OrderBook kept in sync with
+//it. Register OnReady/OnUpdate before the initial snapshot load, which completes asynchronously.
+func (c *Client) OrderBook(symbol string) (*OrderBook, error) {
+	book := &OrderBook{API: c.API, Symbol: symbol, Limit: 1000}
+
+	sub := Subscription{Symbol: symbol, Channel: "depth"}
+	err := c.Subscribe(sub, func(d []byte) {
+		frame := DepthFrame{}
+		if err := json.Unmarshal(d, &frame); err != nil {
+			return
+		}
+
+		book.Apply(frame)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go book.Load()
+
+	return book, nil
+}
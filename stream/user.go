@@ -0,0 +1,85 @@
+package stream
+
+import "encoding/json"
+
+//UserDataHandler is called with a typed user data stream event: OutboundAccountInfo, ExecutionReport or
+//BalanceUpdate depending on which event the payload's "e" field names.
+type UserDataHandler func(event interface{})
+
+type eventType struct {
+	Type string `json:"e"`
+}
+
+//Balance is a single asset balance entry reported on OutboundAccountInfo.
+type Balance struct {
+	Asset  string `json:"a"`
+	Free   string `json:"f"`
+	Locked string `json:"l"`
+}
+
+//OutboundAccountInfo is sent on the user data stream whenever account balances change.
+type OutboundAccountInfo struct {
+	EventType        string    `json:"e"`
+	EventTime        int64     `json:"E"`
+	MakerCommission  int       `json:"m"`
+	TakerCommission  int       `json:"t"`
+	BuyerCommission  int       `json:"b"`
+	SellerCommission int       `json:"s"`
+	CanTrade         bool      `json:"T"`
+	CanWithdraw      bool      `json:"W"`
+	CanDeposit       bool      `json:"D"`
+	Balances         []Balance `json:"B"`
+}
+
+//ExecutionReport is sent on the user data stream for every order update: new, cancelled, filled, etc.
+type ExecutionReport struct {
+	EventType           string `json:"e"`
+	EventTime           int64  `json:"E"`
+	Symbol              string `json:"s"`
+	ClientOrderID       string `json:"c"`
+	Side                string `json:"S"`
+	OrderType           string `json:"o"`
+	TimeInForce         string `json:"f"`
+	Quantity            string `json:"q"`
+	Price               string `json:"p"`
+	ExecutionType       string `json:"x"`
+	OrderStatus         string `json:"X"`
+	OrderID             int64  `json:"i"`
+	LastExecutedQty     string `json:"l"`
+	CumulativeFilledQty string `json:"z"`
+	LastExecutedPrice   string `json:"L"`
+}
+
+//BalanceUpdate is sent on the user data stream when a deposit or withdrawal changes a balance outside of a trade.
+type BalanceUpdate struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Asset     string `json:"a"`
+	Delta     string `json:"d"`
+}
+
+//User streams the authenticated user data stream for listenKey, decoding each payload according to its "e"
+//field and dispatching the typed event to handler. Payloads with an unrecognised event type are ignored.
+func (c *Client) User(listenKey string, handler UserDataHandler) error {
+	return c.API.Stream(listenKey, func(d []byte) {
+		t := eventType{}
+		if err := json.Unmarshal(d, &t); err != nil {
+			return
+		}
+
+		switch t.Type {
+		case "outboundAccountInfo":
+			out := OutboundAccountInfo{}
+			json.Unmarshal(d, &out)
+			go handler(out)
+		case "executionReport":
+			out := ExecutionReport{}
+			json.Unmarshal(d, &out)
+			go handler(out)
+		case "balanceUpdate":
+			out := BalanceUpdate{}
+			json.Unmarshal(d, &out)
+			go handler(out)
+		}
+	})
+}